@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// alertState tracks a worker's rolling average of recent CO2 readings and
+// decides when to alert. It fires only once the average has crossed limit
+// and stayed above limit-hysteresis for window consecutive samples, which
+// avoids the spam a bare "reading > limit" check produces when the value
+// oscillates around the threshold. Once alerting, repeat notifications are
+// throttled by cooldown, and a single "recovered" message is sent once the
+// average drops back below limit-hysteresis.
+type alertState struct {
+	window     int
+	hysteresis int
+	cooldown   time.Duration
+
+	readings    []int
+	sum         int
+	aboveStreak int
+	alerting    bool
+	lastAlertAt time.Time
+}
+
+func newAlertState(window, hysteresis int, cooldown time.Duration) *alertState {
+	return &alertState{window: window, hysteresis: hysteresis, cooldown: cooldown}
+}
+
+// setWindow changes the moving-average window, discarding buffered readings
+// since they no longer correspond to a consistent window size.
+func (a *alertState) setWindow(window int) {
+	a.window = window
+	a.readings = nil
+	a.sum = 0
+	a.aboveStreak = 0
+}
+
+// addReading folds v into the moving average and returns it.
+func (a *alertState) addReading(v int) float64 {
+	if len(a.readings) == a.window {
+		a.sum -= a.readings[0]
+		a.readings = a.readings[1:]
+	}
+	a.readings = append(a.readings, v)
+	a.sum += v
+	return float64(a.sum) / float64(len(a.readings))
+}
+
+// evaluate updates the alert/recovered state machine for the latest average
+// and returns the message to send, or "" if nothing should be sent.
+func (a *alertState) evaluate(limit int, avg float64) string {
+	if avg >= float64(limit-a.hysteresis) {
+		a.aboveStreak++
+	} else {
+		a.aboveStreak = 0
+	}
+
+	sustainedAboveLimit := avg > float64(limit) && a.aboveStreak >= a.window
+
+	switch {
+	case sustainedAboveLimit && !a.alerting:
+		a.alerting = true
+		a.lastAlertAt = time.Now()
+		return fmt.Sprintf("Achtung! CO2 average is %.0f (limit %d)!", avg, limit)
+	case sustainedAboveLimit && a.alerting && time.Since(a.lastAlertAt) >= a.cooldown:
+		a.lastAlertAt = time.Now()
+		return fmt.Sprintf("Achtung! CO2 average is still %.0f (limit %d)!", avg, limit)
+	case a.alerting && avg < float64(limit-a.hysteresis):
+		a.alerting = false
+		return fmt.Sprintf("Recovered: CO2 average is back to %.0f", avg)
+	}
+	return ""
+}
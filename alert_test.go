@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+func TestAlertStateFiresAfterSustainedBreach(t *testing.T) {
+	a := newAlertState(3, 100, 0)
+
+	// First two elevated samples shouldn't fire yet: the streak hasn't
+	// reached the window.
+	if msg := a.evaluate(1000, a.addReading(1100)); msg != "" {
+		t.Fatalf("expected no alert yet, got %q", msg)
+	}
+	if msg := a.evaluate(1000, a.addReading(1100)); msg != "" {
+		t.Fatalf("expected no alert yet, got %q", msg)
+	}
+	if msg := a.evaluate(1000, a.addReading(1100)); msg == "" {
+		t.Fatal("expected an alert once the streak reaches the window")
+	}
+}
+
+func TestAlertStateRepeatsAlertOnceCooldownElapses(t *testing.T) {
+	a := newAlertState(1, 100, 0)
+	a.evaluate(1000, a.addReading(1100))
+	if !a.alerting {
+		t.Fatal("expected alerting state after first breach")
+	}
+	if msg := a.evaluate(1000, a.addReading(1100)); msg == "" {
+		t.Fatal("expected a repeat alert once cooldown (0) has elapsed")
+	}
+}
+
+func TestAlertStateRecovers(t *testing.T) {
+	a := newAlertState(1, 100, 0)
+	a.evaluate(1000, a.addReading(1100))
+	if !a.alerting {
+		t.Fatal("expected alerting state after breach")
+	}
+	msg := a.evaluate(1000, a.addReading(800))
+	if msg == "" {
+		t.Fatal("expected a recovered message")
+	}
+	if a.alerting {
+		t.Fatal("expected alerting to clear on recovery")
+	}
+}
+
+func TestAlertStateOscillationDoesNotSpam(t *testing.T) {
+	a := newAlertState(3, 100, 0)
+	for i := 0; i < 10; i++ {
+		// Bounces between well above and well below the limit every
+		// sample, so the moving average never sustains a breach for a
+		// full window.
+		v := 500
+		if i%2 == 0 {
+			v = 1200
+		}
+		if msg := a.evaluate(1000, a.addReading(v)); msg != "" {
+			t.Fatalf("unexpected alert on oscillating input: %q", msg)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Settings configures a Bot instance.
+type Settings struct {
+	Token  string
+	URL    string // API base, e.g. "https://api.telegram.org"
+	Poller Poller
+}
+
+// Bot dispatches incoming updates to registered command handlers and
+// exposes the Telegram Bot API over whichever Poller was configured.
+type Bot struct {
+	token  string
+	url    string
+	poller Poller
+
+	handlers      map[string]func(*Message)
+	inlineHandler func(*InlineQuery)
+
+	stop chan struct{}
+}
+
+// NewBot validates Settings and returns a ready-to-Start Bot.
+func NewBot(pref Settings) (*Bot, error) {
+	if pref.Token == "" {
+		return nil, errors.New("bot: token is required")
+	}
+	if pref.URL == "" {
+		pref.URL = "https://api.telegram.org"
+	}
+	if pref.Poller == nil {
+		return nil, errors.New("bot: poller is required")
+	}
+	return &Bot{
+		token:    pref.Token,
+		url:      pref.URL,
+		poller:   pref.Poller,
+		handlers: make(map[string]func(*Message)),
+		stop:     make(chan struct{}),
+	}, nil
+}
+
+// Handle registers handler for the given command endpoint, e.g. "/start".
+func (b *Bot) Handle(endpoint string, handler func(*Message)) {
+	b.handlers[endpoint] = handler
+}
+
+// HandleInlineQuery registers the handler invoked for inline_query updates.
+func (b *Bot) HandleInlineQuery(handler func(*InlineQuery)) {
+	b.inlineHandler = handler
+}
+
+// Start runs the configured Poller and dispatches updates to handlers. It
+// blocks until Stop is called.
+func (b *Bot) Start() {
+	updates := make(chan Update)
+	go b.poller.Poll(b, updates, b.stop)
+
+	for {
+		select {
+		case u, ok := <-updates:
+			if !ok {
+				return
+			}
+			b.dispatch(u)
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+// Stop signals the Poller and Start's loop to shut down. It returns without
+// waiting for Start to exit.
+func (b *Bot) Stop() {
+	close(b.stop)
+}
+
+func (b *Bot) dispatch(u Update) {
+	switch {
+	case u.Message != nil:
+		handler, ok := b.handlers[u.Message.Text]
+		if !ok {
+			handler, ok = b.handlers["/default"]
+		}
+		if ok {
+			handler(u.Message)
+		}
+	case u.InlineQuery != nil && b.inlineHandler != nil:
+		b.inlineHandler(u.InlineQuery)
+	}
+}
+
+// Send sends a plain text message to chatID.
+func (b *Bot) Send(chatID int, text string) error {
+	query := url.Values{}
+	query.Set("chat_id", strconv.Itoa(chatID))
+	query.Set("text", text)
+	_, err := b.raw("sendMessage", query)
+	return err
+}
+
+// AnswerInlineQuery responds to an inline query (one sent by a user typing
+// "@botname ...") with the given results.
+func (b *Bot) AnswerInlineQuery(queryID string, results []InlineQueryResultArticle) error {
+	payload, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+	query := url.Values{}
+	query.Set("inline_query_id", queryID)
+	query.Set("results", string(payload))
+	_, err = b.raw("answerInlineQuery", query)
+	return err
+}
+
+// Raw issues an arbitrary Bot API method call, e.g. for reply markup or
+// other parameters the higher-level helpers don't cover.
+func (b *Bot) Raw(method string, query url.Values) (string, error) {
+	return b.raw(method, query)
+}
+
+func (b *Bot) raw(method string, query url.Values) (string, error) {
+	resp, err := http.Get(b.url + "/bot" + b.token + "/" + method + "?" + query.Encode())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
@@ -0,0 +1,39 @@
+package bot
+
+import "testing"
+
+func TestDispatchRoutesMessagesByText(t *testing.T) {
+	b, err := NewBot(Settings{Token: "t", Poller: &LongPoller{}})
+	if err != nil {
+		t.Fatalf("NewBot: %v", err)
+	}
+
+	var got string
+	b.Handle("/co2", func(m *Message) { got = "co2" })
+	b.Handle("/default", func(m *Message) { got = "default" })
+
+	b.dispatch(Update{Message: &Message{Text: "/co2", Chat: &Chat{ID: 1}}})
+	if got != "co2" {
+		t.Fatalf("got %q, want co2", got)
+	}
+
+	b.dispatch(Update{Message: &Message{Text: "anything else", Chat: &Chat{ID: 1}}})
+	if got != "default" {
+		t.Fatalf("got %q, want default", got)
+	}
+}
+
+func TestDispatchRoutesInlineQueries(t *testing.T) {
+	b, err := NewBot(Settings{Token: "t", Poller: &LongPoller{}})
+	if err != nil {
+		t.Fatalf("NewBot: %v", err)
+	}
+
+	var got string
+	b.HandleInlineQuery(func(q *InlineQuery) { got = q.Text })
+
+	b.dispatch(Update{InlineQuery: &InlineQuery{ID: "1", Text: "hello"}})
+	if got != "hello" {
+		t.Fatalf("got %q, want hello", got)
+	}
+}
@@ -0,0 +1,57 @@
+package bot
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+)
+
+// SendPhoto uploads data as a photo to chatID via sendPhoto's
+// multipart/form-data endpoint, unlike the URL-encoded GETs the other
+// helpers use.
+func (b *Bot) SendPhoto(chatID int, caption, filename string, data []byte) error {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	if err := writer.WriteField("chat_id", strconv.Itoa(chatID)); err != nil {
+		return err
+	}
+	if caption != "" {
+		if err := writer.WriteField("caption", caption); err != nil {
+			return err
+		}
+	}
+
+	part, err := writer.CreateFormFile("photo", filename)
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(data); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, b.url+"/bot"+b.token+"/sendPhoto", body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<12))
+		return fmt.Errorf("bot: sendPhoto returned status %d: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package bot
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSendPhotoUploadsMultipart(t *testing.T) {
+	var gotChatID, gotCaption string
+	var gotPhoto []byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("ParseMultipartForm: %v", err)
+			return
+		}
+		gotChatID = r.FormValue("chat_id")
+		gotCaption = r.FormValue("caption")
+
+		file, _, err := r.FormFile("photo")
+		if err != nil {
+			t.Errorf("FormFile: %v", err)
+			return
+		}
+		defer file.Close()
+		gotPhoto, _ = io.ReadAll(file)
+
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	b, err := NewBot(Settings{Token: "t", URL: server.URL, Poller: &LongPoller{}})
+	if err != nil {
+		t.Fatalf("NewBot: %v", err)
+	}
+
+	if err := b.SendPhoto(42, "a graph", "co2.png", []byte("fake-png-bytes")); err != nil {
+		t.Fatalf("SendPhoto: %v", err)
+	}
+
+	if gotChatID != "42" {
+		t.Fatalf("chat_id = %q, want 42", gotChatID)
+	}
+	if gotCaption != "a graph" {
+		t.Fatalf("caption = %q, want %q", gotCaption, "a graph")
+	}
+	if string(gotPhoto) != "fake-png-bytes" {
+		t.Fatalf("photo = %q, want %q", gotPhoto, "fake-png-bytes")
+	}
+}
+
+func TestSendPhotoReturnsErrorOnNonOKStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"ok":false,"description":"bad request"}`))
+	}))
+	defer server.Close()
+
+	b, err := NewBot(Settings{Token: "t", URL: server.URL, Poller: &LongPoller{}})
+	if err != nil {
+		t.Fatalf("NewBot: %v", err)
+	}
+
+	if err := b.SendPhoto(1, "", "co2.png", []byte("x")); err == nil {
+		t.Fatal("expected an error for a non-200 response")
+	}
+}
@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Poller fetches updates from Telegram and pushes them onto updates until
+// stop is closed. Implementations decide how updates are obtained: long
+// polling against getUpdates, or a webhook listener.
+type Poller interface {
+	Poll(b *Bot, updates chan Update, stop chan struct{})
+}
+
+// LongPoller polls getUpdates in a loop, blocking for up to Timeout on the
+// server side each call.
+type LongPoller struct {
+	Timeout time.Duration
+}
+
+func (p *LongPoller) Poll(b *Bot, updates chan Update, stop chan struct{}) {
+	offset := 0
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		query := url.Values{}
+		if offset != 0 {
+			query.Set("offset", strconv.Itoa(offset))
+		}
+		query.Set("timeout", strconv.Itoa(int(p.Timeout.Seconds())))
+
+		raw, err := b.raw("getUpdates", query)
+		if err != nil {
+			log.Printf("bot: getUpdates: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		var resp apiResponse
+		if err := json.Unmarshal([]byte(raw), &resp); err != nil {
+			log.Printf("bot: decode getUpdates response: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		for _, u := range resp.Result {
+			if u.UpdateID >= offset {
+				offset = u.UpdateID + 1
+			}
+			select {
+			case updates <- u:
+			case <-stop:
+				return
+			}
+		}
+	}
+}
+
+// WebhookPoller receives updates pushed by Telegram to an HTTP endpoint
+// instead of long-polling for them. Telegram is expected to POST the
+// Update payload to Path (e.g. via setWebhook) on Listen.
+type WebhookPoller struct {
+	Listen string
+	Path   string
+}
+
+func (p *WebhookPoller) Poll(b *Bot, updates chan Update, stop chan struct{}) {
+	path := p.Path
+	if path == "" {
+		path = "/"
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "read body", http.StatusBadRequest)
+			return
+		}
+		var u Update
+		if err := json.Unmarshal(body, &u); err != nil {
+			log.Printf("bot: decode webhook update: %v", err)
+			http.Error(w, "bad update", http.StatusBadRequest)
+			return
+		}
+		select {
+		case updates <- u:
+		case <-stop:
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	srv := &http.Server{Addr: p.Listen, Handler: mux}
+	go func() {
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("bot: webhook listener on %s: %v", p.Listen, err)
+	}
+}
@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWebhookPollerDeliversPostedUpdates(t *testing.T) {
+	p := &WebhookPoller{Listen: "127.0.0.1:0"}
+
+	listening, err := freePort()
+	if err != nil {
+		t.Fatalf("freePort: %v", err)
+	}
+	p.Listen = listening
+
+	updates := make(chan Update)
+	stop := make(chan struct{})
+	go p.Poll(nil, updates, stop)
+
+	body := []byte(`{"update_id":1,"message":{"text":"/co2","chat":{"id":42}}}`)
+	postErr := make(chan error, 1)
+	go func() { postErr <- postWithRetry("http://"+p.Listen+"/", body) }()
+
+	select {
+	case u := <-updates:
+		if u.Message == nil || u.Message.Text != "/co2" || u.Message.Chat.ID != 42 {
+			close(stop)
+			t.Fatalf("got %+v, want /co2 from chat 42", u)
+		}
+	case <-time.After(2 * time.Second):
+		close(stop)
+		t.Fatal("timed out waiting for update")
+	}
+
+	if err := <-postErr; err != nil {
+		close(stop)
+		t.Fatalf("POST webhook: %v", err)
+	}
+
+	close(stop)
+}
+
+// freePort asks the OS for an unused loopback address so the test doesn't
+// collide with anything else listening locally.
+func freePort() (string, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr, nil
+}
+
+// postWithRetry retries the POST briefly while the webhook server's
+// net.Listener is still coming up.
+func postWithRetry(url string, body []byte) error {
+	var err error
+	for i := 0; i < 20; i++ {
+		var resp *http.Response
+		resp, err = http.Post(url, "application/json", bytes.NewReader(body))
+		if err == nil {
+			resp.Body.Close()
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return err
+}
@@ -0,0 +1,44 @@
+package bot
+
+// Chat represents a Telegram chat that a Message was sent in.
+type Chat struct {
+	ID int `json:"id"`
+}
+
+// Message is a Telegram message, trimmed down to the fields this bot needs.
+type Message struct {
+	Text string `json:"text"`
+	Chat *Chat  `json:"chat"`
+}
+
+// InlineQuery is a Telegram inline query, sent when a user types
+// "@botname ..." in any chat.
+type InlineQuery struct {
+	ID   string `json:"id"`
+	Text string `json:"query"`
+}
+
+// InlineQueryResultArticle is the simplest inline query result: a single
+// article whose selection sends MessageText into the chat.
+type InlineQueryResultArticle struct {
+	Type                string `json:"type"` // always "article"
+	ID                  string `json:"id"`
+	Title               string `json:"title"`
+	InputMessageContent struct {
+		MessageText string `json:"message_text"`
+	} `json:"input_message_content"`
+}
+
+// Update is a single item returned by getUpdates/delivered to a webhook.
+// At most one of Message or InlineQuery is non-nil; update kinds this bot
+// doesn't model leave both nil.
+type Update struct {
+	UpdateID    int          `json:"update_id"`
+	Message     *Message     `json:"message"`
+	InlineQuery *InlineQuery `json:"inline_query"`
+}
+
+type apiResponse struct {
+	Ok     bool
+	Result []Update
+}
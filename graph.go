@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+)
+
+// sample is one CO2 reading taken at a point in time.
+type sample struct {
+	at  time.Time
+	co2 int
+}
+
+// ringBuffer keeps the most recent CO2 samples for one chat so /graph can
+// render recent history without hitting the sensor or the database.
+type ringBuffer struct {
+	mu       sync.Mutex
+	samples  []sample
+	capacity int
+}
+
+func newRingBuffer(capacity int) *ringBuffer {
+	return &ringBuffer{capacity: capacity}
+}
+
+func (r *ringBuffer) add(s sample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.samples = append(r.samples, s)
+	if len(r.samples) > r.capacity {
+		r.samples = r.samples[len(r.samples)-r.capacity:]
+	}
+}
+
+// since returns the samples recorded after cutoff, oldest first.
+func (r *ringBuffer) since(cutoff time.Time) []sample {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []sample
+	for _, s := range r.samples {
+		if s.at.After(cutoff) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// renderGraph draws samples as a PNG line chart of CO2 over time.
+func renderGraph(samples []sample) ([]byte, error) {
+	p := plot.New()
+	p.Title.Text = "CO2 (ppm)"
+	p.X.Label.Text = "Time"
+	p.Y.Label.Text = "ppm"
+	p.X.Tick.Marker = plot.TimeTicks{Format: "15:04"}
+
+	pts := make(plotter.XYs, len(samples))
+	for i, s := range samples {
+		pts[i].X = float64(s.at.Unix())
+		pts[i].Y = float64(s.co2)
+	}
+
+	line, err := plotter.NewLine(pts)
+	if err != nil {
+		return nil, fmt.Errorf("graph: build line: %w", err)
+	}
+	p.Add(line)
+
+	writerTo, err := p.WriterTo(6*vg.Inch, 4*vg.Inch, "png")
+	if err != nil {
+		return nil, fmt.Errorf("graph: render: %w", err)
+	}
+
+	buf := new(bytes.Buffer)
+	if _, err := writerTo.WriteTo(buf); err != nil {
+		return nil, fmt.Errorf("graph: encode: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestRingBufferTrimsToCapacity(t *testing.T) {
+	r := newRingBuffer(3)
+	base := time.Now()
+	for i := 0; i < 5; i++ {
+		r.add(sample{at: base.Add(time.Duration(i) * time.Minute), co2: 500 + i})
+	}
+
+	got := r.since(base.Add(-time.Hour))
+	if len(got) != 3 {
+		t.Fatalf("len(got) = %d, want 3", len(got))
+	}
+	if got[0].co2 != 502 || got[2].co2 != 504 {
+		t.Fatalf("unexpected samples: %+v", got)
+	}
+}
+
+func TestRingBufferSinceFiltersByCutoff(t *testing.T) {
+	r := newRingBuffer(10)
+	base := time.Now()
+	r.add(sample{at: base.Add(-time.Hour), co2: 500})
+	r.add(sample{at: base, co2: 600})
+
+	got := r.since(base.Add(-time.Minute))
+	if len(got) != 1 || got[0].co2 != 600 {
+		t.Fatalf("unexpected samples: %+v", got)
+	}
+}
+
+func TestRenderGraphProducesPNG(t *testing.T) {
+	base := time.Now()
+	samples := []sample{
+		{at: base, co2: 500},
+		{at: base.Add(time.Minute), co2: 600},
+		{at: base.Add(2 * time.Minute), co2: 550},
+	}
+
+	png, err := renderGraph(samples)
+	if err != nil {
+		t.Fatalf("renderGraph: %v", err)
+	}
+	if !bytes.HasPrefix(png, []byte("\x89PNG")) {
+		t.Fatalf("output doesn't look like a PNG")
+	}
+}
@@ -1,239 +1,459 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"flag"
-	"io/ioutil"
-	"net/http"
+	"fmt"
+	"log"
 	"net/url"
+	"os"
+	"os/signal"
 	"regexp"
 	"strconv"
+	"sync"
+	"syscall"
 	"time"
+
+	"github.com/Morf-A/telegram-CO2-Alert-bot/bot"
+	"github.com/Morf-A/telegram-CO2-Alert-bot/pkg/database"
+	"github.com/Morf-A/telegram-CO2-Alert-bot/pkg/sensor"
 )
 
 type config struct {
-	botID     string
-	sensorURI string
+	botID string
 }
 
 var globalConfig config
 
-type message struct {
-	Text string
-	Chat *chat
-}
-
-type chat struct {
-	ID int
-}
+// co2Source is the configured sensor backend, set once in main before any
+// worker is started.
+var co2Source sensor.Source
 
-type update struct {
-	UpdateID int `json:"update_id"`
-	Message  message
-}
+// Defaults used for a freshly-created subscription; /set lets a chat
+// override them afterwards.
+const (
+	defaultWindow     = 5
+	defaultHysteresis = 100
+	defaultCooldown   = 5 * time.Minute
+)
 
-type sensor struct {
-	Pres  int
-	Ptemp float32
-	Temp  int
-	Hum   int
-	CO2   int
-}
+var setRegexp = regexp.MustCompile(`^/set (window|hysteresis|cooldown) (\S+)$`)
+var graphRegexp = regexp.MustCompile(`^/graph (\S+)$`)
 
-type apiResponse struct {
-	Ok     bool
-	Result []update
-}
+// historyCapacity bounds how many past readings each worker keeps in memory
+// for /graph; at the default one-minute tick that's a day's worth.
+const historyCapacity = 1440
 
+// workerPool owns one worker goroutine per subscribed chat. workers is
+// guarded by mu since start/stop/sendMsg can be called concurrently with
+// each other.
 type workerPool struct {
+	mu      sync.RWMutex
 	workers map[int]worker
+	store   *database.Store
 }
 
 func (pool *workerPool) sendMsg(chatID int, msg string) {
-	if w, ok := pool.workers[chatID]; ok {
-		w.messages <- msg
+	pool.mu.RLock()
+	w, ok := pool.workers[chatID]
+	pool.mu.RUnlock()
+	if !ok {
+		return
 	}
+	select {
+	case w.messages <- msg:
+	case <-w.ctx.Done():
+	}
+}
+
+// samplesSince returns the readings recorded for chatID after cutoff, or
+// false if the chat has no active worker.
+func (pool *workerPool) samplesSince(chatID int, cutoff time.Time) ([]sample, bool) {
+	pool.mu.RLock()
+	w, ok := pool.workers[chatID]
+	pool.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return w.history.since(cutoff), true
 }
 
-func (pool *workerPool) start(chatID, limit int) {
-	pool.stop(chatID)
+// start begins monitoring sub.ChatID against sub.LimitPPM, deriving the
+// worker's lifetime from ctx so a single pool-wide cancellation stops every
+// worker. sub carries the alert window/hysteresis/cooldown and any pending
+// sleep, so both a fresh /start and a restart-time restore go through the
+// same path.
+func (pool *workerPool) start(ctx context.Context, sub database.Subscription) {
+	pool.removeWorker(sub.ChatID)
+
+	workerCtx, cancel := context.WithCancel(ctx)
 	messageChan := make(chan string)
+	newWorker := worker{chatID: sub.ChatID, messages: messageChan, limit: sub.LimitPPM, ctx: workerCtx, cancel: cancel, history: newRingBuffer(historyCapacity)}
+
+	pool.mu.Lock()
 	if pool.workers == nil {
 		pool.workers = make(map[int]worker)
 	}
-	newWorker := worker{chatID: chatID, messages: messageChan, limit: limit}
-	pool.workers[chatID] = newWorker
+	pool.workers[sub.ChatID] = newWorker
+	pool.mu.Unlock()
+
+	if pool.store != nil {
+		if err := pool.store.Save(sub); err != nil {
+			log.Printf("workerPool: persist subscription for %d: %v", sub.ChatID, err)
+		}
+	}
+
 	delayDefault := 60
-	dalayAfterAlert := 300
+	delay := delayDefault
+	if d := time.Until(sub.SleepUntil); d > 0 {
+		delay = int(d.Seconds())
+	}
+	sleepUntil := sub.SleepUntil
+	cooldown := time.Duration(sub.CooldownSeconds) * time.Second
+	alert := newAlertState(sub.Window, sub.Hysteresis, cooldown)
+
+	persist := func() {
+		if pool.store == nil {
+			return
+		}
+		err := pool.store.Save(database.Subscription{
+			ChatID:          newWorker.chatID,
+			LimitPPM:        newWorker.limit,
+			SleepUntil:      sleepUntil,
+			Window:          alert.window,
+			Hysteresis:      alert.hysteresis,
+			CooldownSeconds: int(alert.cooldown.Seconds()),
+		})
+		if err != nil {
+			log.Printf("workerPool: persist config for %d: %v", newWorker.chatID, err)
+		}
+	}
+
 	go func() {
-		delay := 0
 		for {
 			select {
+			case <-workerCtx.Done():
+				return
 			case msg := <-newWorker.messages:
-				switch msg {
-				case "stop":
-					return
-				case "/sleep 15 min":
+				switch {
+				case msg == "/sleep 15 min":
 					delay = 900
-				case "/sleep 30 min":
+				case msg == "/sleep 30 min":
 					delay = 1800
-				case "/sleep 1 hour":
+				case msg == "/sleep 1 hour":
 					delay = 3600
-				case "/sleep 2 hour":
+				case msg == "/sleep 2 hour":
 					delay = 7200
-				case "/sleep 5 hour":
+				case msg == "/sleep 5 hour":
 					delay = 18000
+				default:
+					if m := setRegexp.FindStringSubmatch(msg); m != nil {
+						applySetCommand(alert, newWorker.chatID, m[1], m[2])
+						persist()
+					}
+					continue
 				}
+				sleepUntil = time.Now().Add(time.Duration(delay) * time.Second)
+				persist()
 			case <-time.After(time.Duration(delay) * time.Second):
 				delay = delayDefault
-				sensorParams := getCO2()
-				if sensorParams.CO2 > newWorker.limit {
-					sendMessage(newWorker.chatID, "Achtung! CO2 is "+strconv.Itoa(sensorParams.CO2)+"!")
-					delay = dalayAfterAlert
+				reading, err := getCO2(workerCtx)
+				if err != nil {
+					log.Printf("workerPool: read CO2 for %d: %v", newWorker.chatID, err)
+					continue
+				}
+				newWorker.history.add(sample{at: time.Now(), co2: reading.CO2})
+				avg := alert.addReading(reading.CO2)
+				if msg := alert.evaluate(newWorker.limit, avg); msg != "" {
+					tb.Send(newWorker.chatID, msg)
 				}
 			}
 		}
 	}()
 }
 
+// applySetCommand parses and applies a "/set <field> <value>" message for
+// one chat, replying with either the new value or a usage error.
+func applySetCommand(alert *alertState, chatID int, field, value string) {
+	switch field {
+	case "window":
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			tb.Send(chatID, "Usage: /set window <positive integer>")
+			return
+		}
+		alert.setWindow(n)
+		tb.Send(chatID, "Window set to "+strconv.Itoa(n))
+	case "hysteresis":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			tb.Send(chatID, "Usage: /set hysteresis <non-negative integer>")
+			return
+		}
+		alert.hysteresis = n
+		tb.Send(chatID, "Hysteresis set to "+strconv.Itoa(n))
+	case "cooldown":
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			tb.Send(chatID, "Usage: /set cooldown <duration, e.g. 10m>")
+			return
+		}
+		alert.cooldown = d
+		tb.Send(chatID, "Cooldown set to "+d.String())
+	}
+}
+
 type worker struct {
 	messages chan string
 	limit    int
 	chatID   int
+	ctx      context.Context
+	cancel   context.CancelFunc
+	history  *ringBuffer
+}
+
+// removeWorker cancels and forgets any in-memory worker for chatID without
+// touching the store. start uses this to replace a pre-existing worker
+// (e.g. a restart respawning the same chat) so that reloading persisted
+// subscriptions never deletes a row it's about to rewrite.
+func (pool *workerPool) removeWorker(chatID int) {
+	pool.mu.Lock()
+	w, ok := pool.workers[chatID]
+	if ok {
+		delete(pool.workers, chatID)
+	}
+	pool.mu.Unlock()
+
+	if ok {
+		w.cancel()
+	}
 }
 
 func (pool *workerPool) stop(chatID int) {
-	pool.sendMsg(chatID, "stop")
-	delete(pool.workers, chatID)
+	pool.removeWorker(chatID)
+	if pool.store != nil {
+		if err := pool.store.Delete(chatID); err != nil {
+			log.Printf("workerPool: delete subscription for %d: %v", chatID, err)
+		}
+	}
 }
 
+// tb is the bot instance used by the worker pool to push alerts. It's set
+// once in main before any worker is started.
+var tb *bot.Bot
+
 func main() {
 	botIDPtr := flag.String("bot", "", "bot ID")
-	sensorURIPtr := flag.String("sensor", "", "sendor URI")
+	sensorTypePtr := flag.String("sensor-type", "http", "sensor backend: http, prom, or serial")
+	sensorURIPtr := flag.String("sensor", "", "sensor URI (sensor-type=http or prom)")
+	sensorMetricPtr := flag.String("sensor-metric", "co2_ppm", "Prometheus metric name (sensor-type=prom)")
+	sensorDevicePtr := flag.String("sensor-device", "/dev/ttyUSB0", "UART device path (sensor-type=serial)")
+	webhookPtr := flag.String("webhook", "", "listen address for webhook mode; long-polling is used when empty")
+	webhookPathPtr := flag.String("webhook-path", "/", "HTTP path Telegram POSTs updates to in webhook mode")
+	dbPtr := flag.String("db", "co2bot.db", "path to the SQLite database used to persist subscriptions")
 	flag.Parse()
-	globalConfig = config{botID: *botIDPtr, sensorURI: *sensorURIPtr}
-	sleepRegexp := regexp.MustCompile("/sleep\\s(\\d+)\\s(min|hours?)")
-	updates := getUpdatesChan()
-	pool := new(workerPool)
-	startConversation := make(map[int]bool)
-	for update := range updates {
-		if _, ok := startConversation[update.Message.Chat.ID]; ok {
-			delete(startConversation, update.Message.Chat.ID)
-			limit, err := strconv.Atoi(update.Message.Text)
-			if err != nil {
-				sendMessage(update.Message.Chat.ID, "Integer expected. Run command again.")
-				continue
-			}
-			if limit <= 0 {
-				sendMessage(update.Message.Chat.ID, "Expected value more than 0. Run command again.")
-				continue
-			}
-			if limit > 10000 {
-				sendMessage(update.Message.Chat.ID, "Value can`t be more than 10000. Run command again.")
-				continue
-			}
-			sendMessage(update.Message.Chat.ID, "Start watch CO2 less than "+update.Message.Text)
-			pool.start(update.Message.Chat.ID, limit)
-		} else if update.Message.Text == "/start" {
-			sendMessage(update.Message.Chat.ID, "Enter maximum CO2 value")
-			startConversation[update.Message.Chat.ID] = true
-		} else if update.Message.Text == "/stop" {
-			pool.stop(update.Message.Chat.ID)
-		} else if update.Message.Text == "/co2" {
-			sensorParams := getCO2()
-			sendMessage(update.Message.Chat.ID, "CO2 is "+strconv.Itoa(sensorParams.CO2))
-		} else if update.Message.Text == "/sleep" {
-			sendSleepKeyboard(update.Message.Chat.ID)
-		} else if sleepRegexp.MatchString(update.Message.Text) {
-			pool.sendMsg(update.Message.Chat.ID, update.Message.Text)
-		} else if update.Message.Text == "/help" {
-			sendMessage(update.Message.Chat.ID, "Usage:\n"+
-				"/start - Monitor the level of co2\n"+
-				"/stop - Stop monitoring\n"+
-				"/sleep - Disable for a while\n"+
-				"/co2 - Show current CO2 value\n"+
-				"/help - Show help")
-		}
+	globalConfig = config{botID: *botIDPtr}
+
+	switch *sensorTypePtr {
+	case "http":
+		co2Source = &sensor.HTTPJSONSource{URI: *sensorURIPtr}
+	case "prom":
+		co2Source = &sensor.PrometheusSource{URI: *sensorURIPtr, Metric: *sensorMetricPtr}
+	case "serial":
+		co2Source = &sensor.SerialSource{Device: *sensorDevicePtr}
+	default:
+		panic(fmt.Sprintf("unknown -sensor-type %q, want http, prom, or serial", *sensorTypePtr))
+	}
+
+	var poller bot.Poller
+	if *webhookPtr != "" {
+		poller = &bot.WebhookPoller{Listen: *webhookPtr, Path: *webhookPathPtr}
+	} else {
+		poller = &bot.LongPoller{Timeout: 60 * time.Second}
 	}
-}
 
-func getCO2() sensor {
-	response := httpGet(globalConfig.sensorURI)
-	var s sensor
-	err := json.Unmarshal([]byte(response), &s)
+	var err error
+	tb, err = bot.NewBot(bot.Settings{Token: globalConfig.botID, Poller: poller})
 	if err != nil {
 		panic(err)
 	}
-	return s
-}
 
-func sendSleepKeyboard(chatID int) string {
-	query := url.Values{}
-	keyBoard := "{\"keyboard\":[[\"/sleep 15 min\"],[\"/sleep 30 min\"]," +
-		"[\"/sleep 1 hour\"],[\"/sleep 2 hour\"], [\"/sleep 5 hour\"]]," +
-		"\"one_time_keyboard\":true}"
-	query.Set("chat_id", strconv.Itoa(chatID))
-	query.Add("reply_markup", keyBoard)
-	query.Add("text", "Select sleep time")
-	return apiCall("sendMessage", query)
-}
+	store, err := database.Open(*dbPtr)
+	if err != nil {
+		panic(err)
+	}
+	defer store.Close()
 
-func sendMessage(chatID int, text string) string {
-	query := url.Values{}
-	query.Set("chat_id", strconv.Itoa(chatID))
-	query.Add("text", text)
-	return apiCall("sendMessage", query)
-}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-func getUpdates(offset int, limit int, timeout int) string {
-	query := url.Values{}
-	if offset != 0 {
-		query.Add("offset", strconv.Itoa(offset))
-	}
-	if limit != 0 {
-		query.Add("limit", strconv.Itoa(limit))
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		log.Print("shutting down: cancelling workers and stopping the poller")
+		cancel()
+		tb.Stop()
+	}()
+
+	sleepRegexp := regexp.MustCompile("/sleep\\s(\\d+)\\s(min|hours?)")
+	pool := &workerPool{store: store}
+	startConversation := make(map[int]bool)
+
+	subs, err := store.All()
+	if err != nil {
+		panic(err)
 	}
-	if timeout != 0 {
-		query.Add("timeout", strconv.Itoa(timeout))
+	for _, sub := range subs {
+		pool.start(ctx, sub)
 	}
-	return apiCall("getUpdates", query)
-}
 
-func getUpdatesChan() chan update {
-	offset := 0
-	ch := make(chan update)
-	go func() {
-		for {
-			updateStr := getUpdates(offset, 0, 60)
-			var response apiResponse
-			err := json.Unmarshal([]byte(updateStr), &response)
+	tb.Handle("/start", func(m *bot.Message) {
+		tb.Send(m.Chat.ID, "Enter maximum CO2 value")
+		startConversation[m.Chat.ID] = true
+	})
+
+	tb.Handle("/stop", func(m *bot.Message) {
+		pool.stop(m.Chat.ID)
+	})
+
+	tb.Handle("/co2", func(m *bot.Message) {
+		reading, err := getCO2(ctx)
+		if err != nil {
+			log.Printf("read CO2 for %d: %v", m.Chat.ID, err)
+			tb.Send(m.Chat.ID, "Couldn't read the CO2 sensor, try again shortly.")
+			return
+		}
+		tb.Send(m.Chat.ID, "CO2 is "+strconv.Itoa(reading.CO2))
+	})
+
+	tb.Handle("/sleep", func(m *bot.Message) {
+		sendSleepKeyboard(m.Chat.ID)
+	})
+
+	tb.Handle("/graph", func(m *bot.Message) {
+		tb.Send(m.Chat.ID, "Usage: /graph <duration, e.g. 1h>")
+	})
+
+	// Lets a user share the current reading into any chat by typing
+	// "@botname" instead of DMing the bot.
+	tb.HandleInlineQuery(func(q *bot.InlineQuery) {
+		text := "Couldn't read the CO2 sensor, try again shortly."
+		reading, err := getCO2(ctx)
+		if err != nil {
+			log.Printf("read CO2 for inline query %s: %v", q.ID, err)
+		} else {
+			text = "CO2 is " + strconv.Itoa(reading.CO2)
+		}
+
+		result := bot.InlineQueryResultArticle{Type: "article", ID: q.ID, Title: "Current CO2 level"}
+		result.InputMessageContent.MessageText = text
+		if err := tb.AnswerInlineQuery(q.ID, []bot.InlineQueryResultArticle{result}); err != nil {
+			log.Printf("answer inline query %s: %v", q.ID, err)
+		}
+	})
+
+	tb.Handle("/help", func(m *bot.Message) {
+		tb.Send(m.Chat.ID, "Usage:\n"+
+			"/start - Monitor the level of co2\n"+
+			"/stop - Stop monitoring\n"+
+			"/sleep - Disable for a while\n"+
+			"/co2 - Show current CO2 value\n"+
+			"/set window <n> - Samples averaged before alerting\n"+
+			"/set hysteresis <ppm> - Drop below limit-hysteresis to recover\n"+
+			"/set cooldown <duration> - Minimum time between repeat alerts\n"+
+			"/graph <duration> - Chart recent CO2 history, e.g. /graph 1h\n"+
+			"/help - Show help")
+	})
+
+	// Anything that isn't a known command: either the answer to the
+	// /start limit prompt, a /sleep duration button, a /set or /graph
+	// command with arguments, or noise to ignore.
+	tb.Handle("/default", func(m *bot.Message) {
+		if _, ok := startConversation[m.Chat.ID]; ok {
+			delete(startConversation, m.Chat.ID)
+			limit, err := strconv.Atoi(m.Text)
 			if err != nil {
-				panic(err)
+				tb.Send(m.Chat.ID, "Integer expected. Run command again.")
+				return
 			}
-			for _, update := range response.Result {
-				if update.UpdateID >= offset {
-					offset = update.UpdateID + 1
-					ch <- update
-				}
+			if limit <= 0 {
+				tb.Send(m.Chat.ID, "Expected value more than 0. Run command again.")
+				return
+			}
+			if limit > 10000 {
+				tb.Send(m.Chat.ID, "Value can`t be more than 10000. Run command again.")
+				return
 			}
+			tb.Send(m.Chat.ID, "Start watch CO2 less than "+m.Text)
+			pool.start(ctx, database.Subscription{
+				ChatID:          m.Chat.ID,
+				LimitPPM:        limit,
+				Window:          defaultWindow,
+				Hysteresis:      defaultHysteresis,
+				CooldownSeconds: int(defaultCooldown.Seconds()),
+			})
+			return
 		}
-	}()
-	return ch
+		if sleepRegexp.MatchString(m.Text) || setRegexp.MatchString(m.Text) {
+			pool.sendMsg(m.Chat.ID, m.Text)
+			return
+		}
+		if match := graphRegexp.FindStringSubmatch(m.Text); match != nil {
+			sendGraph(pool, m.Chat.ID, match[1])
+		}
+	})
+
+	tb.Start()
 }
 
-func apiCall(method string, query url.Values) string {
-	return httpGet("https://api.telegram.org/bot" + globalConfig.botID + "/" + method + "?" + query.Encode())
+// getCO2 reads the current reading from the configured sensor backend,
+// bounding how long a single read is allowed to take.
+func getCO2(ctx context.Context) (sensor.Reading, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return co2Source.Read(ctx)
 }
 
-func httpGet(url string) string {
-	response, err := http.Get(url)
-	if err != nil {
-		panic(err)
+// sendGraph parses durationText, renders a chart of the chat's CO2 history
+// over that window, and uploads it as a photo.
+func sendGraph(pool *workerPool, chatID int, durationText string) {
+	d, err := time.ParseDuration(durationText)
+	if err != nil || d <= 0 {
+		tb.Send(chatID, "Usage: /graph <duration, e.g. 1h>")
+		return
+	}
+
+	samples, ok := pool.samplesSince(chatID, time.Now().Add(-d))
+	if !ok {
+		tb.Send(chatID, "No active monitoring for this chat. Run /start first.")
+		return
 	}
-	body, err := ioutil.ReadAll(response.Body)
-	response.Body.Close()
+	if len(samples) == 0 {
+		tb.Send(chatID, "No readings yet for that time range.")
+		return
+	}
+
+	png, err := renderGraph(samples)
 	if err != nil {
-		panic(err)
+		log.Printf("render graph for %d: %v", chatID, err)
+		tb.Send(chatID, "Couldn't render the graph, try again shortly.")
+		return
 	}
-	return string(body)
+
+	if err := tb.SendPhoto(chatID, "CO2 over the last "+d.String(), "co2.png", png); err != nil {
+		log.Printf("send graph for %d: %v", chatID, err)
+		tb.Send(chatID, "Couldn't send the graph, try again shortly.")
+	}
+}
+
+func sendSleepKeyboard(chatID int) {
+	keyBoard := "{\"keyboard\":[[\"/sleep 15 min\"],[\"/sleep 30 min\"]," +
+		"[\"/sleep 1 hour\"],[\"/sleep 2 hour\"], [\"/sleep 5 hour\"]]," +
+		"\"one_time_keyboard\":true}"
+	query := url.Values{}
+	query.Set("chat_id", strconv.Itoa(chatID))
+	query.Add("reply_markup", keyBoard)
+	query.Add("text", "Select sleep time")
+	tb.Raw("sendMessage", query)
 }
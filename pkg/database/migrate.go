@@ -0,0 +1,48 @@
+package database
+
+import "database/sql"
+
+// migrations are applied in order, once each, tracked in schema_migrations.
+// Add new entries to the end; never edit an already-released one.
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS subscriptions (
+		chat_id     INTEGER PRIMARY KEY,
+		limit_ppm   INTEGER NOT NULL,
+		sleep_until TIMESTAMP,
+		created_at  TIMESTAMP NOT NULL
+	)`,
+	`ALTER TABLE subscriptions ADD COLUMN window_size INTEGER NOT NULL DEFAULT 5`,
+	`ALTER TABLE subscriptions ADD COLUMN hysteresis INTEGER NOT NULL DEFAULT 100`,
+	`ALTER TABLE subscriptions ADD COLUMN cooldown_seconds INTEGER NOT NULL DEFAULT 300`,
+}
+
+func migrate(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER NOT NULL)`); err != nil {
+		return err
+	}
+
+	var applied int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied); err != nil {
+		return err
+	}
+
+	for version := applied; version < len(migrations); version++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[version]); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, version+1); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
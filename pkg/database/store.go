@@ -0,0 +1,108 @@
+// Package database persists chat subscriptions to CO2 alerts so the bot can
+// restore them after a restart instead of starting every chat from scratch.
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Subscription is one chat's alert configuration.
+type Subscription struct {
+	ChatID          int
+	LimitPPM        int
+	SleepUntil      time.Time
+	Window          int
+	Hysteresis      int
+	CooldownSeconds int
+}
+
+// Store wraps a SQLite-backed subscriptions table.
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens (creating if necessary) the SQLite database at path and brings
+// its schema up to date. WAL mode plus a busy timeout let the many worker
+// goroutines that call Save concurrently wait out a writer instead of
+// failing with SQLITE_BUSY.
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// busy_timeout is a per-connection setting, so keep the pool to the one
+	// connection the pragmas below are applied to.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`PRAGMA journal_mode = WAL`); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if _, err := db.Exec(`PRAGMA busy_timeout = 5000`); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Close releases the underlying database handle.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// All loads every persisted subscription, used to respawn workers on
+// startup.
+func (s *Store) All() ([]Subscription, error) {
+	rows, err := s.db.Query(`SELECT chat_id, limit_ppm, sleep_until, window_size, hysteresis, cooldown_seconds FROM subscriptions`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []Subscription
+	for rows.Next() {
+		var sub Subscription
+		var sleepUntil sql.NullTime
+		if err := rows.Scan(&sub.ChatID, &sub.LimitPPM, &sleepUntil, &sub.Window, &sub.Hysteresis, &sub.CooldownSeconds); err != nil {
+			return nil, err
+		}
+		sub.SleepUntil = sleepUntil.Time
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+// Save upserts a subscription, used by workerPool.start to write through on
+// every change.
+func (s *Store) Save(sub Subscription) error {
+	_, err := s.db.Exec(`
+		INSERT INTO subscriptions (chat_id, limit_ppm, sleep_until, window_size, hysteresis, cooldown_seconds, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(chat_id) DO UPDATE SET
+			limit_ppm = excluded.limit_ppm,
+			sleep_until = excluded.sleep_until,
+			window_size = excluded.window_size,
+			hysteresis = excluded.hysteresis,
+			cooldown_seconds = excluded.cooldown_seconds
+	`, sub.ChatID, sub.LimitPPM, nullTime(sub.SleepUntil), sub.Window, sub.Hysteresis, sub.CooldownSeconds, time.Now())
+	return err
+}
+
+// Delete removes a subscription, used by workerPool.stop.
+func (s *Store) Delete(chatID int) error {
+	_, err := s.db.Exec(`DELETE FROM subscriptions WHERE chat_id = ?`, chatID)
+	return err
+}
+
+func nullTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStoreSaveAllDelete(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "co2bot.db")
+
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer store.Close()
+
+	if err := store.Save(Subscription{ChatID: 1, LimitPPM: 800}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := store.Save(Subscription{ChatID: 2, LimitPPM: 1200}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	subs, err := store.All()
+	if err != nil {
+		t.Fatalf("All: %v", err)
+	}
+	if len(subs) != 2 {
+		t.Fatalf("expected 2 subscriptions, got %d", len(subs))
+	}
+
+	if err := store.Delete(1); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	subs, err = store.All()
+	if err != nil {
+		t.Fatalf("All: %v", err)
+	}
+	if len(subs) != 1 || subs[0].ChatID != 2 {
+		t.Fatalf("expected only chat 2 to remain, got %+v", subs)
+	}
+}
+
+func TestStoreSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "co2bot.db")
+
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := store.Save(Subscription{ChatID: 42, LimitPPM: 900, SleepUntil: time.Now().Add(time.Hour).Truncate(time.Second)}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	subs, err := reopened.All()
+	if err != nil {
+		t.Fatalf("All: %v", err)
+	}
+	if len(subs) != 1 || subs[0].ChatID != 42 || subs[0].LimitPPM != 900 {
+		t.Fatalf("subscription not restored correctly: %+v", subs)
+	}
+	if subs[0].SleepUntil.IsZero() {
+		t.Fatalf("expected sleep_until to be restored")
+	}
+}
+
+func TestStoreSavesAlertConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "co2bot.db")
+
+	store, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer store.Close()
+
+	if err := store.Save(Subscription{ChatID: 1, LimitPPM: 800, Window: 10, Hysteresis: 150, CooldownSeconds: 600}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	subs, err := store.All()
+	if err != nil {
+		t.Fatalf("All: %v", err)
+	}
+	if len(subs) != 1 {
+		t.Fatalf("expected 1 subscription, got %d", len(subs))
+	}
+	if subs[0].Window != 10 || subs[0].Hysteresis != 150 || subs[0].CooldownSeconds != 600 {
+		t.Fatalf("alert config not saved correctly: %+v", subs[0])
+	}
+}
@@ -0,0 +1,51 @@
+package sensor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// HTTPJSONSource reads a Reading from a GET endpoint returning a JSON body
+// with a "CO2" field, e.g. `{"CO2": 812}`. This is the original behavior of
+// the bot, before other backends existed.
+type HTTPJSONSource struct {
+	URI    string
+	Client *http.Client
+}
+
+func (s *HTTPJSONSource) Read(ctx context.Context) (Reading, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URI, nil)
+	if err != nil {
+		return Reading{}, err
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Reading{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Reading{}, fmt.Errorf("sensor: %s returned status %d", s.URI, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Reading{}, err
+	}
+
+	var payload struct {
+		CO2 int
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return Reading{}, fmt.Errorf("sensor: decode response from %s: %w", s.URI, err)
+	}
+	return Reading{CO2: payload.CO2}, nil
+}
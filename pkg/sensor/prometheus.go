@@ -0,0 +1,77 @@
+package sensor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// PrometheusSource scrapes a Prometheus /metrics endpoint and reads the
+// value of a single configured gauge, e.g. "co2_ppm".
+type PrometheusSource struct {
+	URI    string
+	Metric string
+	Client *http.Client
+}
+
+func (s *PrometheusSource) Read(ctx context.Context) (Reading, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URI, nil)
+	if err != nil {
+		return Reading{}, err
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Reading{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Reading{}, fmt.Errorf("sensor: %s returned status %d", s.URI, resp.StatusCode)
+	}
+
+	value, err := scrapeMetric(resp.Body, s.Metric)
+	if err != nil {
+		return Reading{}, err
+	}
+	return Reading{CO2: int(value)}, nil
+}
+
+// scrapeMetric finds the sample value of a metric in the Prometheus text
+// exposition format, ignoring any label set on it.
+func scrapeMetric(r io.Reader, name string) (float64, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Drop the label set so the value is the field right after the
+		// metric name, ahead of any optional timestamp.
+		if i := strings.IndexByte(line, '{'); i != -1 {
+			if j := strings.LastIndexByte(line, '}'); j > i {
+				line = line[:i] + " " + line[j+1:]
+			}
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != name {
+			continue
+		}
+
+		return strconv.ParseFloat(fields[1], 64)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("sensor: metric %q not found", name)
+}
@@ -0,0 +1,28 @@
+package sensor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScrapeMetric(t *testing.T) {
+	body := `
+# HELP co2_ppm Current CO2 concentration
+# TYPE co2_ppm gauge
+co2_ppm{room="office"} 842
+other_metric 1
+`
+	got, err := scrapeMetric(strings.NewReader(body), "co2_ppm")
+	if err != nil {
+		t.Fatalf("scrapeMetric: %v", err)
+	}
+	if got != 842 {
+		t.Fatalf("got %v, want 842", got)
+	}
+}
+
+func TestScrapeMetricNotFound(t *testing.T) {
+	if _, err := scrapeMetric(strings.NewReader("other_metric 1\n"), "co2_ppm"); err == nil {
+		t.Fatal("expected an error when the metric is missing")
+	}
+}
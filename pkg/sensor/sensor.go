@@ -0,0 +1,18 @@
+// Package sensor abstracts over the different ways a CO2 reading can be
+// obtained, so the bot doesn't need to know whether it's talking to an HTTP
+// endpoint, scraping Prometheus, or reading a UART sensor directly.
+package sensor
+
+import "context"
+
+// Reading is a single CO2 measurement.
+type Reading struct {
+	CO2 int
+}
+
+// Source reads the current ambient CO2 level. Implementations should return
+// an error rather than panic so a transient failure can be logged and
+// retried instead of crashing the caller.
+type Source interface {
+	Read(ctx context.Context) (Reading, error)
+}
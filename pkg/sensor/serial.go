@@ -0,0 +1,70 @@
+package sensor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// mhz19ReadCommand asks an MH-Z19 for its current CO2 reading over UART.
+var mhz19ReadCommand = []byte{0xFF, 0x01, 0x86, 0x00, 0x00, 0x00, 0x00, 0x00, 0x79}
+
+// SerialSource reads a Reading from an MH-Z19 CO2 sensor attached over
+// UART. Open defaults to opening Device directly; tests substitute it with
+// an in-memory port since it doesn't configure baud rate/parity the way a
+// real deployment would need to.
+type SerialSource struct {
+	Device string
+	Open   func(device string) (io.ReadWriteCloser, error)
+}
+
+func (s *SerialSource) Read(ctx context.Context) (Reading, error) {
+	open := s.Open
+	if open == nil {
+		open = openSerialPort
+	}
+
+	port, err := open(s.Device)
+	if err != nil {
+		return Reading{}, fmt.Errorf("sensor: open %s: %w", s.Device, err)
+	}
+	defer port.Close()
+
+	return readMHZ19(port)
+}
+
+// openSerialPort opens the device file directly. It does not configure the
+// UART (baud rate, parity, etc.) via termios, so it relies on the OS or a
+// prior `stty` call having already set the port up for 9600 8N1.
+func openSerialPort(device string) (io.ReadWriteCloser, error) {
+	return os.OpenFile(device, os.O_RDWR, 0)
+}
+
+func readMHZ19(port io.ReadWriter) (Reading, error) {
+	if _, err := port.Write(mhz19ReadCommand); err != nil {
+		return Reading{}, fmt.Errorf("sensor: write MH-Z19 command: %w", err)
+	}
+
+	resp := make([]byte, 9)
+	if _, err := io.ReadFull(port, resp); err != nil {
+		return Reading{}, fmt.Errorf("sensor: read MH-Z19 response: %w", err)
+	}
+	if resp[0] != 0xFF || resp[1] != 0x86 {
+		return Reading{}, fmt.Errorf("sensor: unexpected MH-Z19 response header % x", resp[:2])
+	}
+	if checksum := mhz19Checksum(resp); resp[8] != checksum {
+		return Reading{}, fmt.Errorf("sensor: MH-Z19 checksum mismatch: got %#x, want %#x", resp[8], checksum)
+	}
+
+	co2 := int(resp[2])*256 + int(resp[3])
+	return Reading{CO2: co2}, nil
+}
+
+func mhz19Checksum(resp []byte) byte {
+	var sum byte
+	for _, b := range resp[1:8] {
+		sum += b
+	}
+	return byte(0xFF-sum) + 1
+}
@@ -0,0 +1,62 @@
+package sensor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+)
+
+// fakePort records what was written and returns a canned response on Read.
+type fakePort struct {
+	written  bytes.Buffer
+	response *bytes.Reader
+}
+
+func newFakePort(response []byte) *fakePort {
+	return &fakePort{response: bytes.NewReader(response)}
+}
+
+func (p *fakePort) Write(b []byte) (int, error) { return p.written.Write(b) }
+func (p *fakePort) Read(b []byte) (int, error)  { return p.response.Read(b) }
+func (p *fakePort) Close() error                { return nil }
+
+func mhz19Response(co2 int) []byte {
+	resp := []byte{0xFF, 0x86, byte(co2 / 256), byte(co2 % 256), 0x00, 0x00, 0x00, 0x00, 0x00}
+	resp[8] = mhz19Checksum(resp)
+	return resp
+}
+
+func TestSerialSourceReadsReading(t *testing.T) {
+	port := newFakePort(mhz19Response(812))
+	src := &SerialSource{
+		Device: "/dev/fake",
+		Open:   func(string) (io.ReadWriteCloser, error) { return port, nil },
+	}
+
+	reading, err := src.Read(context.Background())
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if reading.CO2 != 812 {
+		t.Fatalf("CO2 = %d, want 812", reading.CO2)
+	}
+	if !bytes.Equal(port.written.Bytes(), mhz19ReadCommand) {
+		t.Fatalf("wrote %x, want %x", port.written.Bytes(), mhz19ReadCommand)
+	}
+}
+
+func TestSerialSourceRejectsBadChecksum(t *testing.T) {
+	resp := mhz19Response(812)
+	resp[8] ^= 0xFF // corrupt the checksum
+
+	port := newFakePort(resp)
+	src := &SerialSource{
+		Device: "/dev/fake",
+		Open:   func(string) (io.ReadWriteCloser, error) { return port, nil },
+	}
+
+	if _, err := src.Read(context.Background()); err == nil {
+		t.Fatal("expected a checksum error")
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Morf-A/telegram-CO2-Alert-bot/pkg/database"
+)
+
+func testSubscription(chatID int) database.Subscription {
+	return database.Subscription{
+		ChatID:          chatID,
+		LimitPPM:        800,
+		Window:          defaultWindow,
+		Hysteresis:      defaultHysteresis,
+		CooldownSeconds: int(defaultCooldown.Seconds()),
+	}
+}
+
+func TestWorkerPoolConcurrentStartStop(t *testing.T) {
+	pool := &workerPool{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const chats = 200
+	var wg sync.WaitGroup
+	for i := 0; i < chats; i++ {
+		chatID := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pool.start(ctx, testSubscription(chatID))
+			pool.sendMsg(chatID, "/sleep 15 min")
+			pool.stop(chatID)
+		}()
+	}
+	wg.Wait()
+
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+	if len(pool.workers) != 0 {
+		t.Fatalf("expected all workers to be stopped, %d remain", len(pool.workers))
+	}
+}
+
+func TestWorkerPoolCancelStopsAllWorkers(t *testing.T) {
+	pool := &workerPool{}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	const chats = 50
+	for i := 0; i < chats; i++ {
+		pool.start(ctx, testSubscription(i))
+	}
+
+	cancel()
+
+	deadline := time.Now().Add(time.Second)
+	for i := 0; i < chats; i++ {
+		pool.mu.RLock()
+		w, ok := pool.workers[i]
+		pool.mu.RUnlock()
+		if !ok {
+			continue
+		}
+		select {
+		case <-w.ctx.Done():
+		case <-time.After(time.Until(deadline)):
+			t.Fatalf("worker %d did not observe cancellation", i)
+		}
+	}
+}
+
+// TestWorkerPoolRestartRestoresSubscriptions runs the same startup sequence
+// as main (store.All() followed by one pool.start per row) against a real
+// store, not just the database package in isolation. It repeats that
+// sequence itself rather than calling main, and checks only that every chat
+// is respawned with its limit.
+func TestWorkerPoolRestartRestoresSubscriptions(t *testing.T) {
+	store, err := database.Open(filepath.Join(t.TempDir(), "co2bot.db"))
+	if err != nil {
+		t.Fatalf("database.Open: %v", err)
+	}
+	defer store.Close()
+
+	pool := &workerPool{store: store}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	subs := []database.Subscription{
+		{ChatID: 1, LimitPPM: 800, Window: 5, Hysteresis: 100, CooldownSeconds: 300},
+		{ChatID: 2, LimitPPM: 1200, Window: 10, Hysteresis: 150, CooldownSeconds: 600},
+	}
+	for _, sub := range subs {
+		pool.start(ctx, sub)
+	}
+
+	// Simulate a process restart: cancel the old pool's workers (as the
+	// signal handler in main does) without going through pool.stop, which
+	// would delete the rows we're about to reload.
+	cancel()
+
+	restored, err := store.All()
+	if err != nil {
+		t.Fatalf("store.All: %v", err)
+	}
+	if len(restored) != len(subs) {
+		t.Fatalf("expected %d persisted subscriptions, got %d", len(subs), len(restored))
+	}
+
+	newPool := &workerPool{store: store}
+	newCtx, newCancel := context.WithCancel(context.Background())
+	defer newCancel()
+	for _, sub := range restored {
+		newPool.start(newCtx, sub)
+	}
+
+	newPool.mu.RLock()
+	defer newPool.mu.RUnlock()
+	if len(newPool.workers) != len(subs) {
+		t.Fatalf("expected %d workers respawned, got %d", len(subs), len(newPool.workers))
+	}
+	for _, sub := range subs {
+		w, ok := newPool.workers[sub.ChatID]
+		if !ok {
+			t.Fatalf("chat %d was not respawned after restart", sub.ChatID)
+		}
+		if w.limit != sub.LimitPPM {
+			t.Fatalf("chat %d: limit = %d, want %d", sub.ChatID, w.limit, sub.LimitPPM)
+		}
+	}
+}